@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// Well-known labels attached to every message, modeled on the metadata
+// labels a log-shipper-style Kafka input attaches to each record so it can
+// be relabeled or routed downstream.
+const (
+	LabelTopic     = "__meta_kafka_topic"
+	LabelPartition = "__meta_kafka_partition"
+	LabelGroupID   = "__meta_kafka_group_id"
+	LabelMemberID  = "__meta_kafka_member_id"
+	LabelTimestamp = "__meta_kafka_timestamp"
+)
+
+// Labels is the mutable set of string key/value pairs an Enricher derives
+// from a Message's RecordMeta and headers, and that Transforms and Router
+// routes then operate on.
+type Labels map[string]string
+
+// LabelsFromRecordMeta builds the well-known __meta_kafka_* labels for meta,
+// plus one entry per header key/value pair. Header keys never override the
+// __meta_kafka_* labels derived from meta, so a record can't spoof its own
+// topic/partition/group/member metadata via a crafted header.
+func LabelsFromRecordMeta(meta protocol.RecordMeta, headers []Header) Labels {
+	labels := make(Labels, len(headers)+5)
+	for _, h := range headers {
+		labels[h.Key] = string(h.Value)
+	}
+	labels[LabelTopic] = meta.Topic
+	labels[LabelPartition] = strconv.Itoa(int(meta.Partition))
+	labels[LabelGroupID] = meta.GroupID
+	labels[LabelMemberID] = meta.MemberID
+	labels[LabelTimestamp] = strconv.FormatInt(meta.Timestamp.UnixNano(), 10)
+	return labels
+}
+
+// Transform mutates labels in place for a single message. Transforms run in
+// registration order; one that reports keep=false causes the Enricher to
+// drop the message rather than running the remaining transforms.
+type Transform interface {
+	Apply(labels Labels) (keep bool)
+}
+
+// TransformFunc adapts a plain function to the Transform interface.
+type TransformFunc func(labels Labels) bool
+
+func (f TransformFunc) Apply(labels Labels) bool { return f(labels) }
+
+// Enricher applies a sequence of Transforms to the labels derived from each
+// Message's RecordMeta and headers.
+type Enricher struct {
+	transforms []Transform
+}
+
+// Use registers a Transform to run, in order, for every message passed to
+// Enrich.
+func (e *Enricher) Use(t Transform) {
+	e.transforms = append(e.transforms, t)
+}
+
+// Enrich runs the registered transforms over the labels derived from meta
+// and msg.Headers, returning the resulting labels and whether the message
+// survived (false if a transform dropped it).
+func (e *Enricher) Enrich(msg Message, meta protocol.RecordMeta) (Labels, bool) {
+	labels := LabelsFromRecordMeta(meta, msg.Headers)
+	for _, t := range e.transforms {
+		if !t.Apply(labels) {
+			return labels, false
+		}
+	}
+	return labels, true
+}
@@ -0,0 +1,21 @@
+package kafka
+
+import "time"
+
+// Header is a key/value pair attached to a Kafka message.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message represents a message read from or written to a kafka topic/partition.
+type Message struct {
+	Topic         string
+	Partition     int
+	Offset        int64
+	HighWaterMark int64
+	Key           []byte
+	Value         []byte
+	Headers       []Header
+	Time          time.Time
+}
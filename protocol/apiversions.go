@@ -0,0 +1,19 @@
+package protocol
+
+// ApiVersionsRequest asks the broker which versions of each API it
+// supports, so the client can negotiate the wire format to use for the rest
+// of the connection.
+//
+// v0-v2 carry no fields. v3 (KIP-511) adds the client's software name and
+// version. Unmarshal in this package always decodes in classic
+// (non-flexible) mode regardless of version, so unlike the real v3 wire
+// format these two fields are read as ordinary length-prefixed strings
+// rather than compact ones followed by a tagged-field trailer.
+type ApiVersionsRequest struct {
+	ClientSoftwareName    string `kafka:"min=v3,max=v3"`
+	ClientSoftwareVersion string `kafka:"min=v3,max=v3"`
+}
+
+// ApiVersionsRequestVersions are the API versions ApiVersionsRequest
+// supports, lowest first.
+var ApiVersionsRequestVersions = []int16{0, 1, 2, 3}
@@ -3,7 +3,6 @@ package protocol
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"hash/crc32"
 	"io"
 	"io/ioutil"
@@ -14,17 +13,26 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ErrMalformedFrame is set on a decoder when the input bytes cannot possibly
+// encode a valid frame, e.g. a length prefix that claims more bytes than are
+// left in the frame, or a varint that never terminates within the space
+// available. Unlike a wrapped io error, it signals that the stream itself is
+// poisoned, so callers such as Conn should close the connection instead of
+// retrying the read.
+var ErrMalformedFrame = errors.New("malformed frame")
+
 type discarder interface {
 	Discard(int) (int, error)
 }
 
 type decoder struct {
-	reader io.Reader
-	remain int
-	buffer [8]byte
-	err    error
-	table  *crc32.Table
-	crc32  uint32
+	reader   io.Reader
+	remain   int
+	buffer   [8]byte
+	err      error
+	table    *crc32.Table
+	crc32    uint32
+	fetchCtx *FetchContext
 }
 
 func (d *decoder) Reset(r io.Reader, n int) {
@@ -34,6 +42,7 @@ func (d *decoder) Reset(r io.Reader, n int) {
 	d.err = nil
 	d.table = nil
 	d.crc32 = 0
+	d.fetchCtx = nil
 }
 
 func (d *decoder) Read(b []byte) (int, error) {
@@ -107,8 +116,20 @@ func (d *decoder) decodeArray(v value, elemType reflect.Type, decodeElem decodeF
 	if n := d.readInt32(); n < 0 {
 		v.setArray(array{})
 	} else {
-		a := makeArray(elemType, int(n))
-		for i := 0; i < int(n) && d.remain > 0; i++ {
+		// A well-formed element takes at least one byte to encode, so the
+		// frame can't possibly hold more elements than bytes remaining;
+		// capping here keeps a malformed length prefix from triggering a
+		// huge allocation before the loop below ever gets a chance to stop.
+		count := int(n)
+		if count > d.remain {
+			// When the reader implements Discard, setError drains d.remain
+			// via discardAll, so the bound is captured first or count would
+			// collapse to zero.
+			count = d.remain
+			d.setError(ErrMalformedFrame)
+		}
+		a := makeArray(elemType, count)
+		for i := 0; i < count && d.remain > 0; i++ {
 			decodeElem(d, a.index(i))
 		}
 		v.setArray(a)
@@ -119,8 +140,16 @@ func (d *decoder) decodeCompactArray(v value, elemType reflect.Type, decodeElem
 	if n := d.readUnsignedVarInt(); n < 1 {
 		v.setArray(array{})
 	} else {
-		a := makeArray(elemType, int(n-1))
-		for i := 0; i < int(n-1) && d.remain > 0; i++ {
+		count := int(n - 1)
+		if count > d.remain {
+			// When the reader implements Discard, setError drains d.remain
+			// via discardAll, so the bound is captured first or count would
+			// collapse to zero.
+			count = d.remain
+			d.setError(ErrMalformedFrame)
+		}
+		a := makeArray(elemType, count)
+		for i := 0; i < count && d.remain > 0; i++ {
 			decodeElem(d, a.index(i))
 		}
 		v.setArray(a)
@@ -218,9 +247,24 @@ func (d *decoder) readInt64() int64 {
 	return 0
 }
 
+// checkLen reports whether n is a valid length prefix for the bytes
+// remaining in the frame. A negative n (other than the null markers handled
+// by callers) or one that claims more bytes than the frame has left can only
+// come from a malformed or hostile broker response, so it is rejected before
+// the allocating read that would otherwise follow it.
+func (d *decoder) checkLen(n int) bool {
+	if n < 0 || n > d.remain {
+		d.setError(ErrMalformedFrame)
+		return false
+	}
+	return true
+}
+
 func (d *decoder) readString() string {
 	if n := d.readInt16(); n < 0 {
 		return ""
+	} else if !d.checkLen(int(n)) {
+		return ""
 	} else {
 		return bytesToString(d.read(int(n)))
 	}
@@ -229,6 +273,8 @@ func (d *decoder) readString() string {
 func (d *decoder) readVarString() string {
 	if n := d.readVarInt(); n < 0 {
 		return ""
+	} else if !d.checkLen(int(n)) {
+		return ""
 	} else {
 		return bytesToString(d.read(int(n)))
 	}
@@ -237,6 +283,8 @@ func (d *decoder) readVarString() string {
 func (d *decoder) readCompactString() string {
 	if n := d.readUnsignedVarInt(); n < 1 {
 		return ""
+	} else if !d.checkLen(int(n - 1)) {
+		return ""
 	} else {
 		return bytesToString(d.read(int(n - 1)))
 	}
@@ -245,6 +293,8 @@ func (d *decoder) readCompactString() string {
 func (d *decoder) readBytes() []byte {
 	if n := d.readInt32(); n < 0 {
 		return nil
+	} else if !d.checkLen(int(n)) {
+		return nil
 	} else {
 		return d.read(int(n))
 	}
@@ -253,6 +303,8 @@ func (d *decoder) readBytes() []byte {
 func (d *decoder) readBytesTo(w io.Writer) bool {
 	if n := d.readInt32(); n < 0 {
 		return false
+	} else if !d.checkLen(int(n)) {
+		return false
 	} else {
 		d.writeTo(w, int(n))
 		return d.err == nil
@@ -262,6 +314,8 @@ func (d *decoder) readBytesTo(w io.Writer) bool {
 func (d *decoder) readVarBytes() []byte {
 	if n := d.readVarInt(); n < 0 {
 		return nil
+	} else if !d.checkLen(int(n)) {
+		return nil
 	} else {
 		return d.read(int(n))
 	}
@@ -270,6 +324,8 @@ func (d *decoder) readVarBytes() []byte {
 func (d *decoder) readVarBytesTo(w io.Writer) bool {
 	if n := d.readVarInt(); n < 0 {
 		return false
+	} else if !d.checkLen(int(n)) {
+		return false
 	} else {
 		d.writeTo(w, int(n))
 		return d.err == nil
@@ -279,6 +335,8 @@ func (d *decoder) readVarBytesTo(w io.Writer) bool {
 func (d *decoder) readCompactBytes() []byte {
 	if n := d.readUnsignedVarInt(); n < 1 {
 		return nil
+	} else if !d.checkLen(int(n - 1)) {
+		return nil
 	} else {
 		return d.read(int(n - 1))
 	}
@@ -287,6 +345,8 @@ func (d *decoder) readCompactBytes() []byte {
 func (d *decoder) readCompactBytesTo(w io.Writer) bool {
 	if n := d.readUnsignedVarInt(); n < 1 {
 		return false
+	} else if !d.checkLen(int(n - 1)) {
+		return false
 	} else {
 		d.writeTo(w, int(n-1))
 		return d.err == nil
@@ -316,7 +376,7 @@ func (d *decoder) readVarInt() int64 {
 		n--
 	}
 
-	d.setError(fmt.Errorf("cannot decode varint from input stream"))
+	d.setError(ErrMalformedFrame)
 	return 0
 }
 
@@ -343,7 +403,7 @@ func (d *decoder) readUnsignedVarInt() uint64 {
 		n--
 	}
 
-	d.setError(fmt.Errorf("cannot decode unsigned varint from input stream"))
+	d.setError(ErrMalformedFrame)
 	return 0
 }
 
@@ -470,11 +530,24 @@ func arrayDecodeFuncOf(typ reflect.Type, version int16, flexible bool, tag struc
 	return func(d *decoder, v value) { d.decodeArray(v, elemType, elemFunc) }
 }
 
+// fetchContextReaderFrom is implemented by types (e.g. RecordBatch) that
+// need to stamp the decoder's in-scope FetchContext onto what they decode,
+// such as per-record metadata, rather than having the caller reassemble it
+// from the request and response afterward.
+type fetchContextReaderFrom interface {
+	io.ReaderFrom
+	SetFetchContext(*FetchContext)
+}
+
 func readerDecodeFuncOf(typ reflect.Type) decodeFunc {
 	typ = reflect.PtrTo(typ)
 	return func(d *decoder, v value) {
 		if d.err == nil {
-			_, err := v.iface(typ).(io.ReaderFrom).ReadFrom(d)
+			iface := v.iface(typ)
+			if r, ok := iface.(fetchContextReaderFrom); ok {
+				r.SetFetchContext(d.fetchCtx)
+			}
+			_, err := iface.(io.ReaderFrom).ReadFrom(d)
 			if err != nil {
 				d.setError(err)
 			}
@@ -498,7 +571,7 @@ func readInt64(b []byte) int64 {
 	return int64(binary.BigEndian.Uint64(b))
 }
 
-func Unmarshal(data []byte, version int16, value interface{}) error {
+func decodeFuncOfValue(value interface{}, version int16) decodeFunc {
 	typ := elemTypeOf(value)
 	cache, _ := unmarshalers.Load().(map[versionedType]decodeFunc)
 	key := versionedType{typ: typ, version: version}
@@ -523,14 +596,23 @@ func Unmarshal(data []byte, version int16, value interface{}) error {
 		unmarshalers.Store(newCache)
 	}
 
+	return decode
+}
+
+func Unmarshal(data []byte, version int16, value interface{}) error {
+	decode := decodeFuncOfValue(value, version)
+
 	d, _ := decoders.Get().(*decoder)
 	if d == nil {
 		d = &decoder{reader: bytes.NewReader(nil)}
 	}
 
-	d.remain = len(data)
 	r, _ := d.reader.(*bytes.Reader)
+	if r == nil {
+		r = bytes.NewReader(nil)
+	}
 	r.Reset(data)
+	d.Reset(r, len(data))
 
 	defer func() {
 		r.Reset(nil)
@@ -542,6 +624,67 @@ func Unmarshal(data []byte, version int16, value interface{}) error {
 	return dontExpectEOF(d.err)
 }
 
+// UnmarshalFrom is similar to Unmarshal but decodes directly from r instead
+// of requiring the caller to buffer the full frame in memory first. size is
+// the number of bytes the value occupies in r; the decoder will not read
+// past it.
+//
+// This allows large responses (e.g. Fetch) to be decoded as their bytes
+// arrive on the connection, using a bounded, reusable read buffer instead of
+// one large allocation per response.
+func UnmarshalFrom(r io.Reader, size int, version int16, value interface{}) error {
+	if size < 0 {
+		return ErrMalformedFrame
+	}
+
+	decode := decodeFuncOfValue(value, version)
+
+	d, _ := decoders.Get().(*decoder)
+	if d == nil {
+		d = &decoder{reader: bytes.NewReader(nil)}
+	}
+
+	d.Reset(r, size)
+
+	defer func() {
+		d.Reset(bytes.NewReader(nil), 0)
+		decoders.Put(d)
+	}()
+
+	decode(d, valueOf(value))
+	return dontExpectEOF(d.err)
+}
+
+// UnmarshalFetchFrom is UnmarshalFrom with a FetchContext in scope for the
+// duration of the decode. Types that implement fetchContextReaderFrom (such
+// as a Fetch response's RecordBatch) can use it to attach per-record
+// metadata (topic, partition, high watermark, group/member) as records are
+// decoded, instead of the caller stitching that context back together from
+// the request and response once decoding is done.
+func UnmarshalFetchFrom(r io.Reader, size int, version int16, ctx *FetchContext, value interface{}) error {
+	if size < 0 {
+		return ErrMalformedFrame
+	}
+
+	decode := decodeFuncOfValue(value, version)
+
+	d, _ := decoders.Get().(*decoder)
+	if d == nil {
+		d = &decoder{reader: bytes.NewReader(nil)}
+	}
+
+	d.Reset(r, size)
+	d.fetchCtx = ctx
+
+	defer func() {
+		d.Reset(bytes.NewReader(nil), 0)
+		decoders.Put(d)
+	}()
+
+	decode(d, valueOf(value))
+	return dontExpectEOF(d.err)
+}
+
 var (
 	decoders     sync.Pool    // *decoder
 	unmarshalers atomic.Value // map[versionedType]decodeFunc
@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// recordBatchFixture is the wire bytes of a v2 RecordBatch (KIP-98) with one
+// record: offset 100, key null, value "hi", no headers, generated by
+// encoding each field with the same big-endian/varint/zigzag layout
+// RecordBatch.ReadFrom decodes.
+var recordBatchFixture = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64, // base offset = 100
+	0x00, 0x00, 0x00, 0x00, // batch length (unused)
+	0x00, 0x00, 0x00, 0x00, // partition leader epoch
+	0x02,                   // magic
+	0x00, 0x00, 0x00, 0x00, // crc (unvalidated)
+	0x00, 0x00, // attributes
+	0x00, 0x00, 0x00, 0x00, // last offset delta
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xe8, // first timestamp = 1000ms
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xe8, // max timestamp = 1000ms
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // producer id = -1
+	0xff, 0xff, // producer epoch = -1
+	0xff, 0xff, 0xff, 0xff, // base sequence = -1
+	0x00, 0x00, 0x00, 0x01, // record count = 1
+	0x10,       // record length (varint, 8)
+	0x00,       // record attributes
+	0x00,       // timestamp delta (varint, 0)
+	0x00,       // offset delta (varint, 0)
+	0x01,       // key length (varint, -1 => null)
+	0x04,       // value length (varint, 2)
+	0x68, 0x69, // value = "hi"
+	0x00, // header count (varint, 0)
+}
+
+func TestRecordBatchReadFromStampsFetchContext(t *testing.T) {
+	ctx := &FetchContext{
+		Topic:         "orders",
+		Partition:     3,
+		HighWatermark: 42,
+		GroupID:       "g",
+		MemberID:      "m",
+		Generation:    7,
+	}
+
+	var batch RecordBatch
+	err := UnmarshalFetchFrom(bytes.NewReader(recordBatchFixture), len(recordBatchFixture), 0, ctx, &batch)
+	if err != nil {
+		t.Fatalf("UnmarshalFetchFrom: %v", err)
+	}
+
+	if len(batch.Records) != 1 {
+		t.Fatalf("decoded %d records, want 1", len(batch.Records))
+	}
+
+	rec := batch.Records[0]
+	if rec.Offset != 100 {
+		t.Errorf("offset = %d, want 100", rec.Offset)
+	}
+	if rec.Key != nil {
+		t.Errorf("key = %q, want nil", rec.Key)
+	}
+	if string(rec.Value) != "hi" {
+		t.Errorf("value = %q, want %q", rec.Value, "hi")
+	}
+	if !rec.Time.Equal(time.Unix(1, 0)) {
+		t.Errorf("time = %v, want %v", rec.Time, time.Unix(1, 0))
+	}
+
+	want := RecordMeta{
+		Topic:         ctx.Topic,
+		Partition:     ctx.Partition,
+		GroupID:       ctx.GroupID,
+		MemberID:      ctx.MemberID,
+		HighWatermark: ctx.HighWatermark,
+		Generation:    ctx.Generation,
+		Timestamp:     rec.Time,
+	}
+	if rec.Meta != want {
+		t.Fatalf("meta = %+v, want %+v", rec.Meta, want)
+	}
+}
+
+func TestRecordBatchReadFromWithoutFetchContext(t *testing.T) {
+	var batch RecordBatch
+	err := UnmarshalFetchFrom(bytes.NewReader(recordBatchFixture), len(recordBatchFixture), 0, nil, &batch)
+	if err != nil {
+		t.Fatalf("UnmarshalFetchFrom: %v", err)
+	}
+	if len(batch.Records) != 1 {
+		t.Fatalf("decoded %d records, want 1", len(batch.Records))
+	}
+	if meta := batch.Records[0].Meta; meta.Topic != "" || meta.Partition != 0 {
+		t.Fatalf("meta = %+v, want a zero-value RecordMeta aside from the timestamp", meta)
+	}
+}
@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RecordHeader is a Kafka record header key/value pair.
+type RecordHeader struct {
+	Key   string
+	Value []byte
+}
+
+// Record is a single record decoded from a Fetch response's RecordBatch,
+// stamped with the RecordMeta derived from the FetchContext in scope when it
+// was decoded so a consumer can relabel or route it without reassembling
+// that context itself.
+type Record struct {
+	Offset  int64
+	Key     []byte
+	Value   []byte
+	Headers []RecordHeader
+	Time    time.Time
+	Meta    RecordMeta
+}
+
+// RecordBatch is a Fetch response partition's sequence of records, in the
+// v2 (KIP-98) record batch format. It implements io.ReaderFrom so it can be
+// decoded directly through UnmarshalFetchFrom, and fetchContextReaderFrom so
+// the decoder can stamp the in-scope FetchContext onto every Record as it is
+// read, instead of the caller reassembling it from the request and response
+// afterward.
+type RecordBatch struct {
+	Records []Record
+
+	ctx *FetchContext
+}
+
+// SetFetchContext implements fetchContextReaderFrom.
+func (b *RecordBatch) SetFetchContext(ctx *FetchContext) {
+	b.ctx = ctx
+}
+
+// ReadFrom decodes a single batch from r: its header, then up to the record
+// count the header declares, stopping early if the frame runs out. Bytes
+// after that batch are left unread. It only validates the
+// lengths needed to avoid a hostile allocation; it does not verify the
+// batch CRC or handle compressed batches, control batches, or transactional
+// markers.
+func (b *RecordBatch) ReadFrom(r io.Reader) (int64, error) {
+	d, ok := r.(*decoder)
+	if !ok {
+		return 0, errors.New("protocol: RecordBatch.ReadFrom requires the protocol decoder")
+	}
+
+	baseOffset := d.readInt64()
+	_ = d.readInt32() // batch length
+	_ = d.readInt32() // partition leader epoch
+	_ = d.readInt8()  // magic
+	_ = d.readInt32() // crc
+	_ = d.readInt16() // attributes
+	_ = d.readInt32() // last offset delta
+	firstTimestamp := d.readInt64()
+	_ = d.readInt64() // max timestamp
+	_ = d.readInt64() // producer id
+	_ = d.readInt16() // producer epoch
+	_ = d.readInt32() // base sequence
+	count := d.readInt32()
+
+	for i := int32(0); i < count && d.remain > 0; i++ {
+		_ = d.readVarInt() // record length
+		_ = d.readInt8()   // attributes
+		timestampDelta := d.readVarInt()
+		offsetDelta := d.readVarInt()
+
+		key := d.readVarBytes()
+		value := d.readVarBytes()
+
+		headerCount := d.readVarInt()
+		if headerCount < 0 {
+			headerCount = 0
+		}
+		if int(headerCount) > d.remain {
+			// A well-formed header takes at least two bytes (key length,
+			// value length), so the batch can't hold more headers than
+			// bytes remaining; this keeps a hostile count from driving a
+			// huge allocation up front.
+			headerCount = int64(d.remain)
+		}
+
+		headers := make([]RecordHeader, 0, headerCount)
+		for j := int64(0); j < headerCount && d.remain > 0; j++ {
+			headerKey := d.readVarString()
+			headerValue := d.readVarBytes()
+			headers = append(headers, RecordHeader{Key: headerKey, Value: headerValue})
+		}
+
+		ts := time.Unix(0, (firstTimestamp+timestampDelta)*int64(time.Millisecond))
+		b.Records = append(b.Records, Record{
+			Offset:  baseOffset + offsetDelta,
+			Key:     key,
+			Value:   value,
+			Headers: headers,
+			Time:    ts,
+			Meta:    NewRecordMeta(b.ctx, ts),
+		})
+	}
+
+	return 0, d.err
+}
@@ -0,0 +1,51 @@
+package protocol
+
+import "time"
+
+// FetchContext carries the parts of a Fetch exchange that apply to every
+// record in a partition's record batch but aren't encoded in the batch
+// itself: the topic/partition being fetched, the high watermark reported
+// alongside it, and (for group consumers) the group and member driving the
+// fetch. UnmarshalFetchFrom attaches it to the decoder so a RecordBatch can
+// stamp it onto each record as it is decoded, instead of the caller
+// reassembling it from the request and response afterward.
+type FetchContext struct {
+	Topic         string
+	Partition     int32
+	HighWatermark int64
+	GroupID       string
+	MemberID      string
+	Generation    int32
+}
+
+// RecordMeta is the per-record metadata a Fetch consumer needs in order to
+// relabel or route a record without re-deriving it from the surrounding
+// response. It is populated from the FetchContext in scope when the record
+// is decoded, plus the record's own timestamp.
+type RecordMeta struct {
+	Topic         string
+	Partition     int32
+	GroupID       string
+	MemberID      string
+	HighWatermark int64
+	Generation    int32
+	Timestamp     time.Time
+}
+
+// NewRecordMeta builds the metadata for a record decoded under ctx. ctx may
+// be nil when the record wasn't decoded as part of a fetch (e.g. a test
+// fixture), in which case only Timestamp is populated.
+func NewRecordMeta(ctx *FetchContext, timestamp time.Time) RecordMeta {
+	if ctx == nil {
+		return RecordMeta{Timestamp: timestamp}
+	}
+	return RecordMeta{
+		Topic:         ctx.Topic,
+		Partition:     ctx.Partition,
+		GroupID:       ctx.GroupID,
+		MemberID:      ctx.MemberID,
+		HighWatermark: ctx.HighWatermark,
+		Generation:    ctx.Generation,
+		Timestamp:     timestamp,
+	}
+}
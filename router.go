@@ -0,0 +1,47 @@
+package kafka
+
+import "github.com/segmentio/kafka-go/protocol"
+
+// Sink receives a Message alongside the Labels an Enricher derived for it.
+type Sink interface {
+	Route(msg Message, labels Labels) error
+}
+
+// SinkFunc adapts a plain function to the Sink interface.
+type SinkFunc func(msg Message, labels Labels) error
+
+func (f SinkFunc) Route(msg Message, labels Labels) error { return f(msg, labels) }
+
+// Route pairs a Sink with the predicate deciding which messages reach it.
+type Route struct {
+	Match func(labels Labels) bool
+	Sink  Sink
+}
+
+// Router enriches each Message it's given and fans it out to every Route
+// whose Match predicate accepts the resulting labels, so a single consumer
+// group can feed several downstream sinks based on topic/partition/header
+// content without the caller re-implementing metadata plumbing.
+type Router struct {
+	Enricher Enricher
+	Routes   []Route
+}
+
+// Route enriches msg and dispatches it to every matching Route in
+// registration order. It stops at the first Sink error and returns it;
+// routes after that one are not dispatched. A message dropped by the
+// Enricher is routed nowhere.
+func (r *Router) Route(msg Message, meta protocol.RecordMeta) error {
+	labels, keep := r.Enricher.Enrich(msg, meta)
+	if !keep {
+		return nil
+	}
+	for _, route := range r.Routes {
+		if route.Match(labels) {
+			if err := route.Sink.Route(msg, labels); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestRouterRoutesByLabel(t *testing.T) {
+	meta := protocol.RecordMeta{
+		Topic:     "orders.eu",
+		Partition: 3,
+		Timestamp: time.Unix(0, 0),
+	}
+	msg := Message{Topic: meta.Topic, Partition: int(meta.Partition)}
+
+	var routed []string
+	r := &Router{
+		Routes: []Route{
+			{
+				Match: func(labels Labels) bool {
+					return regexp.MustCompile(`^orders\.`).MatchString(labels[LabelTopic])
+				},
+				Sink: SinkFunc(func(msg Message, labels Labels) error {
+					routed = append(routed, labels[LabelTopic])
+					return nil
+				}),
+			},
+			{
+				Match: func(labels Labels) bool { return labels[LabelTopic] == "payments" },
+				Sink: SinkFunc(func(msg Message, labels Labels) error {
+					t.Fatalf("unexpected route to payments sink")
+					return nil
+				}),
+			},
+		},
+	}
+
+	if err := r.Route(msg, meta); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if len(routed) != 1 || routed[0] != "orders.eu" {
+		t.Fatalf("expected a single route to orders.eu, got %v", routed)
+	}
+}
+
+func TestEnricherDropsMessage(t *testing.T) {
+	meta := protocol.RecordMeta{Topic: "internal.heartbeat", Timestamp: time.Unix(0, 0)}
+	msg := Message{Topic: meta.Topic}
+
+	e := Enricher{}
+	e.Use(Drop(LabelTopic, regexp.MustCompile(`^internal\.`)))
+
+	if _, keep := e.Enrich(msg, meta); keep {
+		t.Fatalf("expected message to be dropped")
+	}
+}
+
+func TestLabelsFromRecordMetaHeaderCannotSpoofMeta(t *testing.T) {
+	meta := protocol.RecordMeta{Topic: "orders.eu", Timestamp: time.Unix(0, 0)}
+	headers := []Header{{Key: LabelTopic, Value: []byte("payments")}}
+
+	labels := LabelsFromRecordMeta(meta, headers)
+	if labels[LabelTopic] != meta.Topic {
+		t.Fatalf("LabelTopic = %q, want %q (header value must not override meta)", labels[LabelTopic], meta.Topic)
+	}
+}
+
+func TestEnricherRelabelAndReplace(t *testing.T) {
+	meta := protocol.RecordMeta{Topic: "orders.eu-west-1", Timestamp: time.Unix(0, 0)}
+	msg := Message{Topic: meta.Topic}
+
+	e := Enricher{}
+	e.Use(Relabel(LabelTopic, "region"))
+	e.Use(Replace("region", regexp.MustCompile(`^orders\.`), ""))
+
+	labels, keep := e.Enrich(msg, meta)
+	if !keep {
+		t.Fatalf("expected message to be kept")
+	}
+	if labels["region"] != "eu-west-1" {
+		t.Fatalf("region = %q, want %q", labels["region"], "eu-west-1")
+	}
+}
@@ -0,0 +1,32 @@
+package kafka
+
+import "regexp"
+
+// Relabel copies the value of source to target, leaving source in place.
+// Missing source labels are a no-op.
+func Relabel(source, target string) Transform {
+	return TransformFunc(func(labels Labels) bool {
+		if v, ok := labels[source]; ok {
+			labels[target] = v
+		}
+		return true
+	})
+}
+
+// Replace rewrites the value of label in place using re.ReplaceAllString.
+// Missing labels are a no-op.
+func Replace(label string, re *regexp.Regexp, repl string) Transform {
+	return TransformFunc(func(labels Labels) bool {
+		if v, ok := labels[label]; ok {
+			labels[label] = re.ReplaceAllString(v, repl)
+		}
+		return true
+	})
+}
+
+// Drop discards any message whose label matches re.
+func Drop(label string, re *regexp.Regexp) Transform {
+	return TransformFunc(func(labels Labels) bool {
+		return !re.MatchString(labels[label])
+	})
+}